@@ -0,0 +1,13 @@
+package httpclient
+
+// Cache is the storage backend for cached responses. Keys are produced by
+// fsKey (or a custom key function) and values are the raw dump of an
+// http.Response as produced by httputil.DumpResponse, except for the
+// "<key>:vary" entries the store writes to record a response's
+// comma-separated Vary header names; compression and persistence, if any,
+// are entirely up to the implementation.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, resp []byte) error
+	Delete(key string)
+}
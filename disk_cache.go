@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DiskCache is the default Cache implementation: it gzips each response
+// dump and writes it to a file under root, mirroring the on-disk layout the
+// store has always used.
+type DiskCache struct {
+	root string
+
+	seenHosts sync.Map
+}
+
+// NewDiskCache returns a Cache that stores gzip-compressed response dumps
+// under root, creating per-host directories on first write.
+func NewDiskCache(root string) *DiskCache {
+	return &DiskCache{root: root}
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	f, err := os.ReadFile(filepath.Join(c.root, key))
+	if err != nil {
+		return nil, false
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(f))
+	if err != nil {
+		return nil, false
+	}
+	defer gzipReader.Close()
+
+	data, err := io.ReadAll(gzipReader)
+	if err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+func (c *DiskCache) Set(key string, resp []byte) error {
+	path := filepath.Join(c.root, key)
+
+	hostDir := filepath.Dir(path)
+	if _, ok := c.seenHosts.Load(hostDir); !ok {
+		if err := os.MkdirAll(hostDir, 0750); err != nil {
+			return fmt.Errorf("failed to create cache dir: %w", err)
+		}
+		c.seenHosts.Store(hostDir, struct{}{})
+	}
+
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	if _, err := gzipWriter.Write(resp); err != nil {
+		return fmt.Errorf("failed to gzip response: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to gzip response: %w", err)
+	}
+
+	if err := os.WriteFile(path, buffer.Bytes(), 0640); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	return nil
+}
+
+func (c *DiskCache) Delete(key string) {
+	_ = os.Remove(filepath.Join(c.root, key))
+}
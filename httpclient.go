@@ -3,19 +3,22 @@ package httpclient
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -26,8 +29,27 @@ const (
 	_etagKey        = "Etag"
 	_ifNoneMatchKey = "If-None-Match"
 
+	// RFC 7234 freshness keys
+	_cacheControlKey    = "Cache-Control"
+	_expiresKey         = "Expires"
+	_ageKey             = "Age"
+	_dateKey            = "Date"
+	_lastModifiedKey    = "Last-Modified"
+	_ifModifiedSinceKey = "If-Modified-Since"
+
+	// _fromCacheKey mirrors the convention used by gregjones/httpcache: set
+	// to "1" on any response RoundTrip served out of the cache.
+	_fromCacheKey = "X-From-Cache"
+
+	_varyKey = "Vary"
+
 	_minimumContentLength = 10
 	_defaultAllowedErrors = 3
+
+	// _defaultRevalidateTimeout bounds a background stale-while-revalidate
+	// refresh, since it deliberately runs detached from the request ctx that
+	// triggered it.
+	_defaultRevalidateTimeout = 30 * time.Second
 )
 
 type (
@@ -36,14 +58,23 @@ type (
 	}
 
 	Store struct {
-		fileSystemRoot string
+		cache Cache
 
 		client            Client
 		maxErrorVersion   int
 		fetchTimestampKey string
 		fetchVersionKey   string
 
-		seenHosts sync.Map
+		rfc7234Freshness bool
+		shared           bool
+
+		cacheMethods map[string]bool
+		cacheKeyFunc func(*http.Request) string
+
+		staleWhileRevalidate time.Duration
+		staleIfError         time.Duration
+
+		flightGroup singleflight.Group
 	}
 
 	StoreFunc func() error
@@ -75,14 +106,87 @@ func WithFetchVersionKey(key string) Option {
 	}
 }
 
+// WithRFC7234Freshness switches the store from the default X-Elucidate-Time/
+// X-Elucidate-Version freshness scheme to one computed the way a private
+// HTTP cache would: from the cached response's own Cache-Control, Expires,
+// Age and Date headers. It's meant for upstreams that expose standard
+// caching headers instead of the Elucidate timestamp/version ones.
+func WithRFC7234Freshness(enabled bool) Option {
+	return func(s *Store) {
+		s.rfc7234Freshness = enabled
+	}
+}
+
+// WithShared marks the store as a shared (rather than private) cache for the
+// purposes of RFC 7234 freshness calculation, so s-maxage and the private
+// Cache-Control directive are honored. It has no effect unless
+// WithRFC7234Freshness is also enabled.
+func WithShared(shared bool) Option {
+	return func(s *Store) {
+		s.shared = shared
+	}
+}
+
+// WithCache overrides the storage backend, letting callers plug in Redis,
+// S3, or anything else that satisfies Cache instead of the default
+// gzip-on-disk one. See NewDiskCache and NewMemoryCache for the built-ins.
+func WithCache(cache Cache) Option {
+	return func(s *Store) {
+		s.cache = cache
+	}
+}
+
+// WithCacheMethods opts additional, non-idempotent HTTP methods (e.g. POST)
+// into caching when the store is used as an http.RoundTripper. GET and HEAD
+// are always cacheable; this extends the set.
+func WithCacheMethods(methods []string) Option {
+	return func(s *Store) {
+		if s.cacheMethods == nil {
+			s.cacheMethods = make(map[string]bool, len(methods))
+		}
+		for _, method := range methods {
+			s.cacheMethods[method] = true
+		}
+	}
+}
+
+// WithCacheKey overrides how requests are turned into cache keys. The
+// default keys GET/HEAD requests by host/path?query (see fsKey) and folds a
+// SHA-256 of the body into the key for any other method, since two POSTs to
+// the same URL with different bodies (GraphQL, say) are different requests.
+func WithCacheKey(keyFunc func(*http.Request) string) Option {
+	return func(s *Store) {
+		s.cacheKeyFunc = keyFunc
+	}
+}
+
+// WithStaleWhileRevalidate lets a cached response that's gone stale still be
+// served immediately for up to d beyond its freshness lifetime, while a
+// background goroutine refreshes it (RFC 5861). It only takes effect when
+// WithRFC7234Freshness is enabled, since that's what gives a response a
+// well-defined freshness lifetime to measure staleness against.
+func WithStaleWhileRevalidate(d time.Duration) Option {
+	return func(s *Store) {
+		s.staleWhileRevalidate = d
+	}
+}
+
+// WithStaleIfError lets a cached 2xx response be served in place of an
+// upstream error or 5xx, for up to d after it was written (RFC 5861).
+func WithStaleIfError(d time.Duration) Option {
+	return func(s *Store) {
+		s.staleIfError = d
+	}
+}
+
 func NewStore(fileSystemRoot string, opts ...Option) *Store {
 	s := &Store{
-		fileSystemRoot:    fileSystemRoot,
+		cache:             NewDiskCache(fileSystemRoot),
 		client:            http.DefaultClient,
 		maxErrorVersion:   _defaultAllowedErrors,
 		fetchTimestampKey: _timestampKey,
 		fetchVersionKey:   _versionKey,
-		seenHosts:         sync.Map{},
+		cacheKeyFunc:      defaultCacheKey,
 	}
 
 	for _, opt := range opts {
@@ -131,71 +235,334 @@ func (s *Store) Do(r *http.Request) (*http.Response, error) {
 	return rsp, nil
 }
 
-func (s *Store) RawCacheData(u *url.URL) ([]byte, error) {
-	key := fsKey(s.fileSystemRoot, u)
-	gzipReader, err := readGZIPFile(key)
-
-	if err != err {
-		return nil, err
+// RoundTrip implements http.RoundTripper, so a Store can be dropped straight
+// into &http.Client{Transport: store}. Responses served from cache get an
+// X-From-Cache: 1 header, matching the convention used by the
+// gregjones/httpcache ecosystem. The write-back to cache happens in a
+// background goroutine so Body.Close() isn't blocked on gzip+fsync.
+// Requests carrying Cache-Control: no-store, or using any method other than
+// GET or HEAD that isn't opted in via WithCacheMethods, bypass the cache
+// entirely.
+func (s *Store) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !s.cacheable(r) {
+		return s.client.Do(r)
 	}
 
-	data, err := io.ReadAll(gzipReader)
+	rsp, cached, storeFunc, err := s.internalCacheFetch(r, time.Time{})
 	if err != nil {
-		return nil, err
+		return rsp, err
 	}
-	if err := gzipReader.Close(); err != nil {
-		return nil, err
+
+	if cached {
+		if rsp.Header == nil {
+			rsp.Header = make(http.Header)
+		}
+		rsp.Header.Set(_fromCacheKey, "1")
 	}
 
-	return data, nil
+	go func() {
+		if err := storeFunc(); err != nil {
+			log.Printf("httpclient: failed to write cache entry: %v", err)
+		}
+	}()
+
+	return rsp, nil
 }
 
-func (s *Store) internalCacheFetch(r *http.Request, lastModified time.Time) (*http.Response, bool, StoreFunc, error) {
-	key := fsKey(s.fileSystemRoot, r.URL)
+func (s *Store) cacheable(r *http.Request) bool {
+	if parseCacheControl(r.Header.Get(_cacheControlKey)).noStore {
+		return false
+	}
 
-	// TODO: We should probably cache this so we don't do it on every request
-	hostDir := filepath.Dir(key)
-	if _, ok := s.seenHosts.Load(hostDir); !ok {
-		if err := os.MkdirAll(hostDir, 0750); err != nil {
-			return nil, false, NoOpStoreFunc, err
-		}
-		s.seenHosts.Store(hostDir, struct{}{})
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
 	}
 
+	return s.cacheMethods[r.Method]
+}
+
+// RawCacheData returns the raw dump stored for u, resolved through the same
+// cacheKeyFunc/Vary-aware key s.internalCacheFetch uses, so it still finds
+// the entry under a custom WithCacheKey or one written with a Vary header.
+// header should carry whatever values the original request sent for any
+// header the stored response's Vary lists; it may be nil for an entry that
+// was never written with a Vary header.
+func (s *Store) RawCacheData(u *url.URL, header http.Header) ([]byte, error) {
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	r := &http.Request{Method: http.MethodGet, URL: u, Header: header}
+	baseKey := s.cacheKeyFunc(r)
+	key := s.varyAwareKey(baseKey, r.Header)
+
+	data, ok := s.cache.Get(key)
+	if !ok {
+		return nil, fmt.Errorf("no cached data for %s", u)
+	}
+
+	return data, nil
+}
+
+func (s *Store) internalCacheFetch(r *http.Request, lastModified time.Time) (*http.Response, bool, StoreFunc, error) {
+	baseKey := s.cacheKeyFunc(r)
+	key := s.varyAwareKey(baseKey, r.Header)
+
 	version := 0
 	etag := ""
-	cachedRsp, err := readAndParseGZIPFile(key, r)
+	cachedRsp, err := s.readCachedResponse(key, r)
 	if err == nil {
-		storedVersion, ok := s.shouldUseCachedValue(cachedRsp, lastModified)
-		if ok {
+		if s.rfc7234Freshness {
+			switch s.rfc7234State(cachedRsp) {
+			case stateFresh:
+				return cachedRsp, true, NoOpStoreFunc, nil
+			case stateStaleRevalidate:
+				// Serve the stale hit now and refresh it in the background;
+				// the caller never pays for the upstream round trip.
+				// backgroundRevalidate gets its own independently-parsed copy
+				// of the cached response, since it mutates its headers once
+				// the refresh lands and must not race the one we're handing
+				// back to the caller here.
+				if refreshRsp, err := s.readCachedResponse(key, r); err == nil {
+					s.backgroundRevalidate(r, baseKey, key, refreshRsp)
+				}
+				return cachedRsp, true, NoOpStoreFunc, nil
+			}
+		} else if storedVersion, ok := s.shouldUseCachedValue(cachedRsp, lastModified); ok {
 			return cachedRsp, true, NoOpStoreFunc, nil
+		} else {
+			version = storedVersion
 		}
 
 		etag = cachedRsp.Header.Get(_etagKey)
-		version = storedVersion
 	}
 
+	// Coalesce concurrent fetches for the same key: only the first caller
+	// hits the upstream and writes the cache; everyone else waits on it and
+	// gets their own independent *http.Response parsed from its dump. key
+	// itself can't be used here on a cold cache: until a response (and its
+	// Vary header, if any) has actually been seen, key collapses to baseKey
+	// for every variant, which would coalesce requests that differ only in
+	// a Vary-governed header into a single upstream call. flightKey folds
+	// in the request's current headers so that only truly identical
+	// requests share a flight.
+	untyped, err, _ := s.flightGroup.Do(s.flightKey(baseKey, r.Header), func() (any, error) {
+		return s.fetchAndPrepare(r, baseKey, etag, version, cachedRsp)
+	})
+	if err != nil {
+		if s.staleIfErrorEligible(cachedRsp) {
+			return cachedRsp, true, NoOpStoreFunc, nil
+		}
+		return nil, false, NoOpStoreFunc, err
+	}
+
+	fr := untyped.(*fetchResult)
+	if fr.serverError && s.staleIfErrorEligible(cachedRsp) {
+		return cachedRsp, true, NoOpStoreFunc, nil
+	}
+
+	clonedRsp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(fr.dump)), r)
+	if err != nil {
+		return nil, false, NoOpStoreFunc, fmt.Errorf("failed to clone shared response: %w", err)
+	}
+
+	return clonedRsp, fr.cached, fr.storeFunc, nil
+}
+
+// backgroundRevalidate refreshes a stale-while-revalidate hit asynchronously.
+// It shares the singleflight group with synchronous fetches, so a flood of
+// stale hits for the same key still only triggers one upstream request, and
+// it runs against a context.Background() derived timeout rather than the
+// originating request's ctx, so the caller returning early doesn't cancel
+// the refresh.
+func (s *Store) backgroundRevalidate(r *http.Request, baseKey, key string, cachedRsp *http.Response) {
+	ctx, cancel := context.WithTimeout(context.Background(), _defaultRevalidateTimeout)
+	refreshReq := r.Clone(ctx)
+	etag := cachedRsp.Header.Get(_etagKey)
+
+	go func() {
+		defer cancel()
+
+		untyped, err, _ := s.flightGroup.Do(key, func() (any, error) {
+			return s.fetchAndPrepare(refreshReq, baseKey, etag, 0, cachedRsp)
+		})
+		if err != nil {
+			return
+		}
+
+		if fr := untyped.(*fetchResult); !fr.serverError {
+			_ = fr.storeFunc()
+		}
+	}()
+}
+
+// fetchResult is what a singleflight.Group.Do call returns: the dump every
+// waiter clones its own *http.Response from, a StoreFunc shared by all of
+// them that writes back to cache exactly once no matter how many callers
+// invoke it, and whether the upstream returned a 5xx (so stale-if-error
+// callers know not to trust or persist it).
+type fetchResult struct {
+	dump        []byte
+	cached      bool
+	storeFunc   StoreFunc
+	serverError bool
+}
+
+func (s *Store) fetchAndPrepare(r *http.Request, baseKey, etag string, version int, cachedRsp *http.Response) (*fetchResult, error) {
 	r.Header = r.Header.Clone()
 	if r.Header == nil {
 		r.Header = make(http.Header)
 	}
 	r.Header.Set(_ifNoneMatchKey, etag)
+	if cachedRsp != nil {
+		if lastModified := cachedRsp.Header.Get(_lastModifiedKey); lastModified != "" {
+			r.Header.Set(_ifModifiedSinceKey, lastModified)
+		}
+	}
+
 	rsp, err := s.client.Do(r)
 	if err != nil {
-		return rsp, false, NoOpStoreFunc, err
+		return nil, err
 	}
 
 	if rsp.StatusCode == http.StatusNotModified {
-		// Should we write back to cache here?
-		return cachedRsp, true, NoOpStoreFunc, nil
+		if s.rfc7234Freshness {
+			refreshRFC7234Headers(cachedRsp, rsp)
+		}
+
+		dump, storeFunc, err := s.finalizeWrite(baseKey, r, version, cachedRsp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write fixture: %w", err)
+		}
+
+		return &fetchResult{dump: dump, cached: true, storeFunc: storeFunc}, nil
+	}
+
+	dump, storeFunc, err := s.finalizeWrite(baseKey, r, version+1, rsp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write fixture: %w", err)
+	}
+
+	return &fetchResult{dump: dump, cached: false, storeFunc: storeFunc, serverError: rsp.StatusCode >= 500}, nil
+}
+
+// finalizeWrite dumps rsp and decides where it lives in the cache: baseKey
+// normally, or a key that also folds in the values of whatever request
+// headers rsp's Vary lists, so two requests to the same URL that only
+// differ in e.g. Accept don't clobber each other. The Vary header names
+// themselves are recorded under a sentinel key so a later lookup knows
+// which request headers to fold in before it has even seen the response.
+func (s *Store) finalizeWrite(baseKey string, r *http.Request, version int, rsp *http.Response) ([]byte, StoreFunc, error) {
+	varyNames := splitVary(rsp.Header.Get(_varyKey))
+	writeKey := baseKey
+	if len(varyNames) > 0 {
+		writeKey = varyKey(baseKey, varyNames, r.Header)
+	}
+
+	dump, storeFunc, err := s.prepareForWriting(writeKey, version, rsp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(varyNames) > 0 {
+		writeResponse := storeFunc
+		sentinel := []byte(strings.Join(varyNames, ","))
+		storeFunc = func() error {
+			if err := s.cache.Set(varySentinelKey(baseKey), sentinel); err != nil {
+				return fmt.Errorf("failed to write vary sentinel: %w", err)
+			}
+			return writeResponse()
+		}
+	}
+
+	return dump, storeFunc, nil
+}
+
+// flightKey returns the key used to coalesce concurrent fetches for baseKey.
+// It can't just be the (possibly not-yet-Vary-aware) cache lookup key: on a
+// cold cache, that key collapses to baseKey regardless of which Vary variant
+// the request will turn out to be, which would incorrectly coalesce two
+// concurrent requests that differ only in a Vary-governed header into one
+// upstream call and hand the second caller the first caller's response.
+// Folding in every header the request currently carries means only
+// genuinely identical requests share a flight.
+func (s *Store) flightKey(baseKey string, header http.Header) string {
+	if len(header) == 0 {
+		return baseKey
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+
+	return varyKey(baseKey, names, header)
+}
+
+// varyAwareKey resolves baseKey to the variant actually stored for header,
+// by checking whether a previous write recorded a Vary header list for it.
+func (s *Store) varyAwareKey(baseKey string, header http.Header) string {
+	namesRaw, ok := s.cache.Get(varySentinelKey(baseKey))
+	if !ok {
+		return baseKey
+	}
+
+	return varyKey(baseKey, splitVary(string(namesRaw)), header)
+}
+
+func varySentinelKey(baseKey string) string {
+	return baseKey + ":vary"
+}
+
+func varyKey(baseKey string, names []string, header http.Header) string {
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, strings.ToLower(name)+"="+header.Get(name))
+	}
+	if len(parts) == 0 {
+		return baseKey
+	}
+
+	sort.Strings(parts)
+	return baseKey + "#" + strings.Join(parts, "&")
+}
+
+func splitVary(header string) []string {
+	if header == "" {
+		return nil
+	}
+
+	names := strings.Split(header, ",")
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			out = append(out, name)
+		}
+	}
+
+	return out
+}
+
+// defaultCacheKey is the default WithCacheKey function: for GET/HEAD it's
+// host/path?query, same as before; for any other method (which RoundTrip
+// only caches once opted into via WithCacheMethods, while CacheFetch caches
+// it regardless) it also folds in a SHA-256 of the body, since e.g. two
+// GraphQL POSTs to the same URL are usually different requests.
+func defaultCacheKey(r *http.Request) string {
+	key := fsKey(r.URL)
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Body == nil {
+		return key
 	}
 
-	finalStoreFunc, err := s.prepareForWriting(key, version+1, rsp)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return nil, false, NoOpStoreFunc, fmt.Errorf("failed to write fixture: %w", err)
+		return key
 	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
 
-	return rsp, false, finalStoreFunc, nil
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf("%s-%x", key, sum)
 }
 
 func (s *Store) shouldUseCachedValue(rsp *http.Response, lastModified time.Time) (int, bool) {
@@ -246,57 +613,215 @@ func (s *Store) getVersion(rsp *http.Response) (int, error) {
 	return 1, nil
 }
 
-func (s *Store) prepareForWriting(key string, version int, rsp *http.Response) (func() error, error) {
-	if rsp.Header == nil {
-		rsp.Header = make(http.Header)
+// freshnessState classifies a cached response relative to this store's
+// RFC 7234 freshness window and, per RFC 5861, the stale-while-revalidate
+// grace period beyond it.
+type freshnessState int
+
+const (
+	stateFresh freshnessState = iota
+	stateStaleRevalidate
+	stateStaleExpired
+)
+
+// rfc7234State reports how fresh the cached response is per RFC 7234 §4.2,
+// using its own Cache-Control/Expires/Age/Date headers instead of the
+// X-Elucidate-* timestamp scheme.
+func (s *Store) rfc7234State(rsp *http.Response) freshnessState {
+	cc := parseCacheControl(rsp.Header.Get(_cacheControlKey))
+	if cc.noStore || cc.noCache || (cc.private && s.shared) {
+		return stateStaleExpired
 	}
-	rsp.Header.Set(s.fetchTimestampKey, fmt.Sprintf("%d", time.Now().Unix()))
-	rsp.Header.Set(s.fetchVersionKey, fmt.Sprintf("%d", version))
 
-	dump, err := httputil.DumpResponse(rsp, true)
+	lifetime, ok := freshnessLifetime(cc, rsp, s.shared)
+	if !ok {
+		return stateStaleExpired
+	}
+
+	age := currentAge(rsp)
+	if age < lifetime {
+		return stateFresh
+	}
+
+	if s.staleWhileRevalidate > 0 && age < lifetime+s.staleWhileRevalidate {
+		return stateStaleRevalidate
+	}
+
+	return stateStaleExpired
+}
+
+// staleIfErrorEligible reports whether cachedRsp is a successful response
+// recent enough, per WithStaleIfError, to serve in place of an upstream
+// error or 5xx.
+func (s *Store) staleIfErrorEligible(cachedRsp *http.Response) bool {
+	if s.staleIfError <= 0 || cachedRsp == nil {
+		return false
+	}
+	if cachedRsp.StatusCode < 200 || cachedRsp.StatusCode >= 300 {
+		return false
+	}
+
+	age, ok := s.ageOf(cachedRsp)
+	if !ok {
+		return false
+	}
+
+	return age <= s.staleIfError
+}
+
+// ageOf reports how long ago cachedRsp was written, using the RFC 7234 Age/
+// Date headers in RFC 7234 mode or the X-Elucidate-Time timestamp otherwise.
+func (s *Store) ageOf(rsp *http.Response) (time.Duration, bool) {
+	if s.rfc7234Freshness {
+		return currentAge(rsp), true
+	}
+
+	t, err := s.getWriteTimestamp(rsp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to dump response: %w", err)
+		return 0, false
 	}
 
-	return func() error {
-		var buffer bytes.Buffer
-		gzipWriter := gzip.NewWriter(&buffer)
-		_, err = gzipWriter.Write(dump)
-		if err != nil {
-			return err
+	return time.Since(t), true
+}
+
+// freshnessLifetime computes how long rsp is considered fresh for, per
+// RFC 7234 §4.2.1: s-maxage (shared caches only), then max-age, then Expires.
+func freshnessLifetime(cc cacheControl, rsp *http.Response, shared bool) (time.Duration, bool) {
+	if shared && cc.sMaxAge >= 0 {
+		return time.Duration(cc.sMaxAge) * time.Second, true
+	}
+	if cc.maxAge >= 0 {
+		return time.Duration(cc.maxAge) * time.Second, true
+	}
+
+	expires := rsp.Header.Get(_expiresKey)
+	if expires == "" {
+		return 0, false
+	}
+
+	expiresAt, err := http.ParseTime(expires)
+	if err != nil {
+		return 0, false
+	}
+
+	date, err := responseDate(rsp)
+	if err != nil {
+		return 0, false
+	}
+
+	return expiresAt.Sub(date), true
+}
+
+// currentAge computes the response's age per RFC 7234 §4.2.3: the Age
+// header (if present) plus the time elapsed since the response's Date.
+func currentAge(rsp *http.Response) time.Duration {
+	age := time.Duration(0)
+	if a := rsp.Header.Get(_ageKey); a != "" {
+		if seconds, err := strconv.Atoi(a); err == nil {
+			age = time.Duration(seconds) * time.Second
 		}
+	}
+
+	date, err := responseDate(rsp)
+	if err != nil {
+		return age
+	}
 
-		if err := gzipWriter.Close(); err != nil {
-			return err
+	return age + time.Since(date)
+}
+
+func responseDate(rsp *http.Response) (time.Time, error) {
+	return http.ParseTime(rsp.Header.Get(_dateKey))
+}
+
+type cacheControl struct {
+	noStore bool
+	noCache bool
+	private bool
+	maxAge  int
+	sMaxAge int
+}
+
+func parseCacheControl(header string) cacheControl {
+	cc := cacheControl{maxAge: -1, sMaxAge: -1}
+
+	for _, directive := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "no-store":
+			cc.noStore = true
+		case "no-cache":
+			cc.noCache = true
+		case "private":
+			cc.private = true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				cc.maxAge = seconds
+			}
+		case "s-maxage":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				cc.sMaxAge = seconds
+			}
 		}
+	}
+
+	return cc
+}
 
-		return os.WriteFile(key, buffer.Bytes(), 0640)
-	}, nil
+// refreshRFC7234Headers copies the freshness-related headers from a 304
+// revalidation response onto the cached one, leaving the stored body as-is.
+func refreshRFC7234Headers(cached, notModified *http.Response) {
+	for _, key := range []string{_dateKey, _ageKey, _cacheControlKey, _expiresKey, _etagKey, _varyKey} {
+		if v := notModified.Header.Get(key); v != "" {
+			cached.Header.Set(key, v)
+		}
+	}
 }
 
-func readGZIPFile(key string) (*gzip.Reader, error) {
-	f, err := os.ReadFile(key)
+// prepareForWriting dumps rsp (stamping the Elucidate timestamp/version
+// headers unless the store is in RFC 7234 mode) and returns that dump
+// alongside a StoreFunc that writes it to cache exactly once, however many
+// times it's called - singleflight followers all share the same one.
+func (s *Store) prepareForWriting(key string, version int, rsp *http.Response) ([]byte, StoreFunc, error) {
+	if rsp.Header == nil {
+		rsp.Header = make(http.Header)
+	}
+	if !s.rfc7234Freshness {
+		rsp.Header.Set(s.fetchTimestampKey, fmt.Sprintf("%d", time.Now().Unix()))
+		rsp.Header.Set(s.fetchVersionKey, fmt.Sprintf("%d", version))
+	}
+
+	dump, err := httputil.DumpResponse(rsp, true)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("failed to dump response: %w", err)
+	}
+
+	var once sync.Once
+	var storeErr error
+	storeFunc := func() error {
+		once.Do(func() {
+			storeErr = s.cache.Set(key, dump)
+		})
+		return storeErr
 	}
 
-	return gzip.NewReader(bytes.NewReader(f))
+	return dump, storeFunc, nil
 }
 
-func readAndParseGZIPFile(key string, r *http.Request) (*http.Response, error) {
-	gzipReader, err := readGZIPFile(key)
-	if err != nil {
-		return nil, err
+func (s *Store) readCachedResponse(key string, r *http.Request) (*http.Response, error) {
+	data, ok := s.cache.Get(key)
+	if !ok {
+		return nil, fmt.Errorf("cache miss for %s", key)
 	}
 
-	return http.ReadResponse(bufio.NewReader(gzipReader), r)
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), r)
 }
 
-func fsKey(fileSystemRoot string, u *url.URL) string {
+func fsKey(u *url.URL) string {
 	cleaned_params := strings.ReplaceAll(u.RawQuery, "/", "-")
 	noLeadingSlash := strings.TrimLeft(u.Path, "/")
 	cleaned_path := strings.ReplaceAll(noLeadingSlash, "/", "-")
-	return filepath.Join(fileSystemRoot, u.Host, strings.ToLower(cleaned_path+"?"+cleaned_params+".gz"))
+	return filepath.Join(u.Host, strings.ToLower(cleaned_path+"?"+cleaned_params+".gz"))
 }
 
 func NoOpStoreFunc() error {
@@ -1,9 +1,12 @@
 package httpclient
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,17 +16,43 @@ import (
 type dummyClient struct {
 	statusCode int
 	body       string
-	requests   int
+	bodyFunc   func(*http.Request) string
+	header     http.Header
+
+	mu       sync.Mutex
+	requests int
 }
 
 func (d *dummyClient) Do(req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
 	d.requests++
+	d.mu.Unlock()
+
+	body := d.body
+	if d.bodyFunc != nil {
+		body = d.bodyFunc(req)
+	}
+
+	header := d.header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
 	return &http.Response{
 		StatusCode: d.statusCode,
-		Body:       io.NopCloser(strings.NewReader(d.body)),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
 	}, nil
 }
 
+// count returns the number of requests made so far; it's synchronized so
+// tests that assert on it while a background revalidation may be in flight
+// don't race with dummyClient.Do.
+func (d *dummyClient) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.requests
+}
+
 func TestStore(t *testing.T) {
 	t.Parallel()
 
@@ -37,7 +66,7 @@ func TestStore(t *testing.T) {
 		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
 		require.NoError(t, err)
 
-		rsp, found, storeFunc, err := store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusOK, rsp.StatusCode)
@@ -45,9 +74,9 @@ func TestStore(t *testing.T) {
 		data, err := io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 1, dummyClient.requests)
+		require.Equal(t, 1, dummyClient.count())
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusOK, rsp.StatusCode)
@@ -55,9 +84,9 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 1, dummyClient.requests)
+		require.Equal(t, 1, dummyClient.count())
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Now())
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Now())
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusOK, rsp.StatusCode)
@@ -65,7 +94,7 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 2, dummyClient.requests)
+		require.Equal(t, 2, dummyClient.count())
 	})
 
 	t.Run("two different endpoints", func(t *testing.T) {
@@ -78,7 +107,7 @@ func TestStore(t *testing.T) {
 		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
 		require.NoError(t, err)
 
-		rsp, found, storeFunc, err := store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusOK, rsp.StatusCode)
@@ -86,12 +115,12 @@ func TestStore(t *testing.T) {
 		data, err := io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 1, dummyClient.requests)
+		require.Equal(t, 1, dummyClient.count())
 
 		req, err = http.NewRequest(http.MethodGet, "http://example2.com", nil)
 		require.NoError(t, err)
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusOK, rsp.StatusCode)
@@ -99,7 +128,7 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 2, dummyClient.requests)
+		require.Equal(t, 2, dummyClient.count())
 	})
 
 	t.Run("fetch error retry max", func(t *testing.T) {
@@ -112,7 +141,7 @@ func TestStore(t *testing.T) {
 		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
 		require.NoError(t, err)
 
-		rsp, found, storeFunc, err := store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rsp.StatusCode)
@@ -120,9 +149,9 @@ func TestStore(t *testing.T) {
 		data, err := io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 1, dummyClient.requests)
+		require.Equal(t, 1, dummyClient.count())
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rsp.StatusCode)
@@ -130,9 +159,9 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 2, dummyClient.requests)
+		require.Equal(t, 2, dummyClient.count())
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rsp.StatusCode)
@@ -140,9 +169,9 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 3, dummyClient.requests)
+		require.Equal(t, 3, dummyClient.count())
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rsp.StatusCode)
@@ -150,9 +179,9 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 3, dummyClient.requests)
+		require.Equal(t, 3, dummyClient.count())
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Now())
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Now())
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rsp.StatusCode)
@@ -160,9 +189,9 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 4, dummyClient.requests)
+		require.Equal(t, 4, dummyClient.count())
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rsp.StatusCode)
@@ -170,9 +199,9 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 5, dummyClient.requests)
+		require.Equal(t, 5, dummyClient.count())
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rsp.StatusCode)
@@ -180,9 +209,9 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 6, dummyClient.requests)
+		require.Equal(t, 6, dummyClient.count())
 
-		rsp, found, storeFunc, err = store.CacheFetch(req, time.Time{})
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Time{})
 		require.NoError(t, storeFunc())
 		require.NoError(t, err)
 		require.Equal(t, http.StatusInternalServerError, rsp.StatusCode)
@@ -190,6 +219,362 @@ func TestStore(t *testing.T) {
 		data, err = io.ReadAll(rsp.Body)
 		require.NoError(t, err)
 		require.Equal(t, "hello, world", string(data))
-		require.Equal(t, 6, dummyClient.requests)
+		require.Equal(t, 6, dummyClient.count())
+	})
+
+	t.Run("RFC 7234 freshness", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+			header: http.Header{
+				"Date":          []string{time.Now().UTC().Format(http.TimeFormat)},
+				"Cache-Control": []string{"max-age=60"},
+			},
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient), WithRFC7234Freshness(true))
+
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+
+		rsp, found, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.False(t, found)
+		require.Equal(t, 1, dummyClient.count())
+
+		rsp, found, storeFunc, err = store.CacheFetch(context.Background(), req, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.True(t, found)
+		require.Equal(t, 1, dummyClient.count())
+		data, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "hello, world", string(data))
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+			header: http.Header{
+				"Date":          []string{time.Now().UTC().Format(http.TimeFormat)},
+				"Cache-Control": []string{"max-age=60"},
+			},
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient), WithRFC7234Freshness(true))
+		client := &http.Client{Transport: store}
+
+		rsp, err := client.Get("http://example.com")
+		require.NoError(t, err)
+		require.Equal(t, "", rsp.Header.Get("X-From-Cache"))
+		require.Equal(t, 1, dummyClient.count())
+
+		// Give the background write-back a chance to land before the next hit.
+		time.Sleep(10 * time.Millisecond)
+
+		rsp, err = client.Get("http://example.com")
+		require.NoError(t, err)
+		require.Equal(t, "1", rsp.Header.Get("X-From-Cache"))
+		require.Equal(t, 1, dummyClient.count())
+	})
+
+	t.Run("RoundTrip stale-while-revalidate doesn't race the cached response's headers", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+			header: http.Header{
+				"Date":          []string{time.Now().Add(-2 * time.Second).UTC().Format(http.TimeFormat)},
+				"Cache-Control": []string{"max-age=1"},
+			},
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient), WithRFC7234Freshness(true),
+			WithStaleWhileRevalidate(time.Minute))
+		client := &http.Client{Transport: store}
+
+		rsp, err := client.Get("http://example.com")
+		require.NoError(t, err)
+		require.NoError(t, rsp.Body.Close())
+
+		time.Sleep(10 * time.Millisecond)
+
+		// The entry is already stale, so this hit is served from cache while
+		// a background revalidation races to update the cached headers. The
+		// RoundTrip caller concurrently sets X-From-Cache on its own response;
+		// under -race these must not be seen as touching the same header map.
+		rsp, err = client.Get("http://example.com")
+		require.NoError(t, err)
+		require.Equal(t, "1", rsp.Header.Get("X-From-Cache"))
+		require.NoError(t, rsp.Body.Close())
+
+		require.Eventually(t, func() bool {
+			return dummyClient.count() == 2
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("RoundTrip skips caching for non-idempotent methods by default", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient))
+		client := &http.Client{Transport: store}
+
+		_, err := client.Post("http://example.com", "text/plain", strings.NewReader("body"))
+		require.NoError(t, err)
+		_, err = client.Post("http://example.com", "text/plain", strings.NewReader("body"))
+		require.NoError(t, err)
+
+		require.Equal(t, 2, dummyClient.count())
+	})
+
+	t.Run("coalesces concurrent fetches for the same key", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient))
+
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rsp, _, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
+				require.NoError(t, err)
+				require.NoError(t, storeFunc())
+				data, err := io.ReadAll(rsp.Body)
+				require.NoError(t, err)
+				require.Equal(t, "hello, world", string(data))
+			}()
+		}
+		wg.Wait()
+
+		require.Equal(t, 1, dummyClient.count())
+	})
+
+	t.Run("concurrent fetches with different Vary variants are not coalesced", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			header:     http.Header{"Vary": []string{"Accept"}},
+			bodyFunc: func(req *http.Request) string {
+				if req.Header.Get("Accept") == "application/xml" {
+					return "<xml/>"
+				}
+				return `{"json":true}`
+			},
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient))
+
+		jsonReq, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+		jsonReq.Header.Set("Accept", "application/json")
+
+		xmlReq, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+		xmlReq.Header.Set("Accept", "application/xml")
+
+		var wg sync.WaitGroup
+		var jsonBody, xmlBody string
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			rsp, _, storeFunc, err := store.CacheFetch(context.Background(), jsonReq, time.Time{})
+			require.NoError(t, err)
+			require.NoError(t, storeFunc())
+			data, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+			jsonBody = string(data)
+		}()
+		go func() {
+			defer wg.Done()
+			rsp, _, storeFunc, err := store.CacheFetch(context.Background(), xmlReq, time.Time{})
+			require.NoError(t, err)
+			require.NoError(t, storeFunc())
+			data, err := io.ReadAll(rsp.Body)
+			require.NoError(t, err)
+			xmlBody = string(data)
+		}()
+		wg.Wait()
+
+		require.Equal(t, `{"json":true}`, jsonBody)
+		require.Equal(t, "<xml/>", xmlBody)
+		require.Equal(t, 2, dummyClient.count())
+	})
+
+	t.Run("Vary keeps distinct variants separate", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+			header:     http.Header{"Vary": []string{"Accept"}},
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient), WithMaxErrorVersion(2))
+
+		jsonReq, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+		jsonReq.Header.Set("Accept", "application/json")
+
+		xmlReq, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+		xmlReq.Header.Set("Accept", "application/xml")
+
+		_, found, storeFunc, err := store.CacheFetch(context.Background(), jsonReq, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.False(t, found)
+		require.Equal(t, 1, dummyClient.count())
+
+		_, found, storeFunc, err = store.CacheFetch(context.Background(), xmlReq, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.False(t, found)
+		require.Equal(t, 2, dummyClient.count())
+
+		_, found, storeFunc, err = store.CacheFetch(context.Background(), jsonReq, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.True(t, found)
+		require.Equal(t, 2, dummyClient.count())
+	})
+
+	t.Run("WithCacheKey overrides the default key function", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient), WithMaxErrorVersion(2),
+			WithCacheKey(func(r *http.Request) string { return "fixed-key" }))
+
+		reqA, err := http.NewRequest(http.MethodGet, "http://example.com/a", nil)
+		require.NoError(t, err)
+		reqB, err := http.NewRequest(http.MethodGet, "http://example.com/b", nil)
+		require.NoError(t, err)
+
+		_, found, storeFunc, err := store.CacheFetch(context.Background(), reqA, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.False(t, found)
+
+		_, found, storeFunc, err = store.CacheFetch(context.Background(), reqB, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.True(t, found)
+		require.Equal(t, 1, dummyClient.count())
+	})
+
+	t.Run("stale-while-revalidate serves the stale hit and refreshes in the background", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+			header: http.Header{
+				"Date":          []string{time.Now().Add(-2 * time.Second).UTC().Format(http.TimeFormat)},
+				"Cache-Control": []string{"max-age=1"},
+			},
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient), WithRFC7234Freshness(true),
+			WithStaleWhileRevalidate(time.Minute))
+
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+
+		_, found, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.False(t, found)
+		require.Equal(t, 1, dummyClient.count())
+
+		// The entry is now older than its 1s max-age, but within the 1m SWR
+		// window, so this hit should come straight from cache...
+		rsp, found, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.True(t, found)
+		data, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "hello, world", string(data))
+
+		// ...while a refresh happens in the background.
+		require.Eventually(t, func() bool {
+			return dummyClient.count() == 2
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("stale-if-error serves the last known good response on upstream failure", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+			header: http.Header{
+				"Date":          []string{time.Now().UTC().Format(http.TimeFormat)},
+				"Cache-Control": []string{"max-age=0"},
+			},
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient), WithRFC7234Freshness(true),
+			WithStaleIfError(time.Minute))
+
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+
+		_, found, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.False(t, found)
+
+		dummyClient.statusCode = http.StatusInternalServerError
+
+		rsp, found, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+		require.True(t, found)
+		data, err := io.ReadAll(rsp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "hello, world", string(data))
+	})
+
+	t.Run("RawCacheData returns the stored dump", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient))
+
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/a", nil)
+		require.NoError(t, err)
+
+		_, _, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+
+		dump, err := store.RawCacheData(req.URL, nil)
+		require.NoError(t, err)
+		require.Contains(t, string(dump), "hello, world")
+
+		_, err = store.RawCacheData(&url.URL{Host: "example.com", Path: "/missing"}, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("RawCacheData resolves Vary'd entries given the original headers", func(t *testing.T) {
+		dummyClient := &dummyClient{
+			statusCode: http.StatusOK,
+			body:       "hello, world",
+			header:     http.Header{"Vary": []string{"Accept"}},
+		}
+		store := NewStore(t.TempDir(), WithClient(dummyClient))
+
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "application/json")
+
+		_, _, storeFunc, err := store.CacheFetch(context.Background(), req, time.Time{})
+		require.NoError(t, err)
+		require.NoError(t, storeFunc())
+
+		_, err = store.RawCacheData(req.URL, nil)
+		require.Error(t, err, "without the original Accept header, the variant key shouldn't resolve")
+
+		dump, err := store.RawCacheData(req.URL, req.Header)
+		require.NoError(t, err)
+		require.Contains(t, string(dump), "hello, world")
 	})
 }
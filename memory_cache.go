@@ -0,0 +1,93 @@
+package httpclient
+
+import (
+	"container/list"
+	"sync"
+)
+
+// MemoryCache is an in-memory, size-bounded LRU Cache. It's suited to tests
+// and short-lived processes that don't want to touch disk.
+type MemoryCache struct {
+	mu sync.Mutex
+
+	maxBytes  int
+	usedBytes int
+	order     *list.List
+	entries   map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key  string
+	data []byte
+}
+
+// NewMemoryCache returns a Cache that keeps up to maxBytes of response
+// dumps in memory, evicting the least recently used entry once exceeded.
+func NewMemoryCache(maxBytes int) *MemoryCache {
+	return &MemoryCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+
+	entry := el.Value.(*memoryCacheEntry)
+	data := make([]byte, len(entry.data))
+	copy(data, entry.data)
+	return data, true
+}
+
+func (c *MemoryCache) Set(key string, resp []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := append([]byte(nil), resp...)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*memoryCacheEntry)
+		c.usedBytes += len(data) - len(entry.data)
+		entry.data = data
+		c.order.MoveToFront(el)
+	} else {
+		el := c.order.PushFront(&memoryCacheEntry{key: key, data: data})
+		c.entries[key] = el
+		c.usedBytes += len(data)
+	}
+
+	for c.usedBytes > c.maxBytes && c.order.Len() > 0 {
+		c.evictOldest()
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *MemoryCache) evictOldest() {
+	if el := c.order.Back(); el != nil {
+		c.removeElement(el)
+	}
+}
+
+func (c *MemoryCache) removeElement(el *list.Element) {
+	entry := el.Value.(*memoryCacheEntry)
+	delete(c.entries, entry.key)
+	c.order.Remove(el)
+	c.usedBytes -= len(entry.data)
+}
@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set and get", func(t *testing.T) {
+		cache := NewMemoryCache(1024)
+
+		require.NoError(t, cache.Set("a", []byte("hello")))
+		data, ok := cache.Get("a")
+		require.True(t, ok)
+		require.Equal(t, "hello", string(data))
+
+		_, ok = cache.Get("missing")
+		require.False(t, ok)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		cache := NewMemoryCache(1024)
+
+		require.NoError(t, cache.Set("a", []byte("hello")))
+		cache.Delete("a")
+
+		_, ok := cache.Get("a")
+		require.False(t, ok)
+	})
+
+	t.Run("evicts least recently used once over budget", func(t *testing.T) {
+		cache := NewMemoryCache(10)
+
+		require.NoError(t, cache.Set("a", []byte("12345")))
+		require.NoError(t, cache.Set("b", []byte("12345")))
+		_, ok := cache.Get("a")
+		require.True(t, ok)
+
+		// "a" was just touched, so "b" should be evicted first.
+		require.NoError(t, cache.Set("c", []byte("12345")))
+
+		_, ok = cache.Get("a")
+		require.True(t, ok)
+		_, ok = cache.Get("b")
+		require.False(t, ok)
+		_, ok = cache.Get("c")
+		require.True(t, ok)
+	})
+}